@@ -5,14 +5,23 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"mime"
 	"mime/multipart"
+	"mime/quotedprintable"
 	"net/http"
+	"net/textproto"
+	"os"
+	"regexp"
 	"strings"
 	"unicode/utf8"
 )
@@ -23,38 +32,442 @@ type ParseResponse struct {
 	Parts        []Part  `json:"parts,omitempty"`
 	ErrorType    *string `json:"error_type,omitempty"`
 	ErrorMessage *string `json:"error_message,omitempty"`
+	// Mode is the transport-layer-variant mode the request was parsed under
+	// (see resolveMode): "strict", "lenient" or "email".
+	Mode string `json:"mode,omitempty"`
+	// NormalizedBytes counts bytes inserted while normalizing bare "\n" line
+	// endings to "\r\n" in "email" mode.
+	NormalizedBytes int `json:"normalized_bytes,omitempty"`
 }
 
 // Part represents a parsed multipart part.
 type Part struct {
-	Name        string  `json:"name"`
-	Filename    *string `json:"filename"`
-	ContentType *string `json:"content_type"`
-	BodyText    *string `json:"body_text"`
-	BodyBase64  *string `json:"body_base64"`
-	BodySize    int     `json:"body_size"`
+	Name     string              `json:"name"`
+	Headers  map[string][]string `json:"headers,omitempty"`
+	Filename *string             `json:"filename"`
+	// FilenameStar holds the RFC 5987/2231 filename*=charset'lang'value
+	// parameter, decoded to UTF-8, when the part sent one.
+	FilenameStar            *string `json:"filename_star,omitempty"`
+	ContentType             *string `json:"content_type"`
+	ContentTransferEncoding *string `json:"content_transfer_encoding,omitempty"`
+	BodyText                *string `json:"body_text"`
+	BodyBase64              *string `json:"body_base64"`
+	// RawBodyText/RawBodyBase64 hold the body as received on the wire,
+	// before Content-Transfer-Encoding decoding. Only populated when
+	// ParserOptions.DecodeTransferEncoding or "email" mode caused
+	// BodyText/BodyBase64 to hold decoded content instead.
+	RawBodyText   *string `json:"raw_body_text,omitempty"`
+	RawBodyBase64 *string `json:"raw_body_base64,omitempty"`
+	BodySize      int     `json:"body_size"`
+	SpilledToDisk bool    `json:"spilled_to_disk,omitempty"`
 }
 
-// parseMultipart handles POST /parse requests.
-func parseMultipart(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+// ParserOptions configures the resource limits applied while parsing a
+// multipart body. A zero value for any field means "no limit", matching
+// the default behavior of Go's mime/multipart package.
+type ParserOptions struct {
+	MaxParts       int   `json:"max_parts"`
+	MaxPartSize    int64 `json:"max_part_size"`
+	MaxTotalSize   int64 `json:"max_total_size"`
+	MaxHeaderBytes int64 `json:"max_header_bytes"`
+	MaxMemory      int64 `json:"max_memory"`
+	// DecodeTransferEncoding, when true, decodes "base64" and
+	// "quoted-printable" Content-Transfer-Encoding part bodies and moves
+	// the raw bytes to RawBodyText/RawBodyBase64.
+	DecodeTransferEncoding bool `json:"decode_transfer_encoding"`
+	// Mode selects the transport-layer-variant handling: "strict",
+	// "lenient" (the default) or "email". A ?mode= query parameter on the
+	// request takes precedence over this field.
+	Mode string `json:"mode"`
+}
+
+// Sentinel errors for the strict/lenient/email mode handling.
+var (
+	errBareLF         = errors.New("bare LF line ending outside strict CRLF transport")
+	errUnsupportedCTE = errors.New("unsupported Content-Transfer-Encoding in strict mode")
+)
+
+// Sentinel errors for the resource limits enforced by ParserOptions. These
+// are returned from the read path so classifyError can recognize them
+// independently of whatever message mime/multipart happens to produce.
+var (
+	errTooManyParts   = errors.New("too many parts")
+	errPartTooLarge   = errors.New("part too large")
+	errBodyTooLarge   = errors.New("body too large")
+	errHeaderTooLarge = errors.New("header too large")
+)
+
+// Sentinel errors for Content-Disposition problems, classified separately
+// from the underlying mime.ParseMediaType failure so classifyError can tell
+// "no header at all" from "header present but malformed". Only
+// errInvalidContentDisposition is returned by this package's parse path:
+// parseContentDispositionFilename accepts a part with no Content-Disposition
+// as a nameless part rather than returning errMissingContentDisposition.
+var (
+	errMissingContentDisposition = errors.New("missing Content-Disposition header")
+	errInvalidContentDisposition = errors.New("invalid Content-Disposition header")
+)
+
+// The stable error_type taxonomy returned in ParseResponse.ErrorType. These
+// values are shared across conformance-suite backends; a given backend may
+// not be able to produce every one of them (see classifyError), in which
+// case the test runner treats a mismatch against one of those as a backend
+// limitation rather than a conformance failure.
+const (
+	ErrorTypeBoundaryMissing           = "boundary_missing"
+	ErrorTypeBoundaryMismatch          = "boundary_mismatch"
+	ErrorTypePreambleEOF               = "preamble_eof"
+	ErrorTypeEpilogueEOF               = "epilogue_eof"
+	ErrorTypeUnterminatedPart          = "unterminated_part"
+	ErrorTypeMalformedHeader           = "malformed_header"
+	ErrorTypeMissingContentDisposition = "missing_content_disposition"
+	ErrorTypeInvalidContentDisposition = "invalid_content_disposition"
+	ErrorTypePartTooLarge              = "part_too_large"
+	ErrorTypeTooManyParts              = "too_many_parts"
+	ErrorTypeHeaderTooLarge            = "header_too_large"
+	ErrorTypeIOError                   = "io_error"
+	ErrorTypeParseError                = "parse_error"
+)
+
+// parserOptionsFromRequest extracts ParserOptions from the X-Parser-Options
+// request header, which the test runner sends as a JSON-encoded object
+// (mirroring TestJSON.ParserOptions). A missing header yields the zero value,
+// i.e. no limits.
+func parserOptionsFromRequest(r *http.Request) (ParserOptions, error) {
+	var opts ParserOptions
+
+	raw := r.Header.Get("X-Parser-Options")
+	if raw == "" {
+		return opts, nil
+	}
+
+	if err := json.Unmarshal([]byte(raw), &opts); err != nil {
+		return opts, fmt.Errorf("invalid X-Parser-Options header: %w", err)
+	}
+
+	return opts, nil
+}
+
+// limitedBodyReader enforces MaxTotalSize on the raw request body, returning
+// errBodyTooLarge instead of silently truncating once the limit is reached.
+type limitedBodyReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedBodyReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		return 0, errBodyTooLarge
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// resolveMode determines the strict/lenient/email mode for a request: a
+// ?mode= query parameter takes precedence over ParserOptions.Mode, which in
+// turn takes precedence over the "lenient" default.
+func resolveMode(r *http.Request, opts ParserOptions) (string, error) {
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = opts.Mode
+	}
+	if mode == "" {
+		mode = "lenient"
+	}
+
+	switch mode {
+	case "strict", "lenient", "email":
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown mode %q: must be strict, lenient or email", mode)
+	}
+}
+
+// multipartFramingScanner splits a multipart body into a sequence of chunks,
+// each tagged as either "framing" (preamble, part headers, the blank line
+// that ends them, or a boundary delimiter line) or body content. It exists
+// so "strict" and "email" mode can hold transport-layer line endings to a
+// stricter standard than a part's own content: a part's body is arbitrary
+// bytes (often binary) and a bare 0x0A inside it is just data, not a line
+// ending in need of validation or rewriting.
+//
+// A body line's own trailing newline is ambiguous until the following line
+// is known: if that next line turns out to be a boundary delimiter, the
+// newline was the framing separator between the body and the delimiter, not
+// body content. next() resolves this by holding it back one line.
+type multipartFramingScanner struct {
+	r             *bufio.Reader
+	boundary      string
+	inBody        bool
+	seenDelimiter bool
+	pendingTerm   []byte
+	eof           bool
+}
+
+func newMultipartFramingScanner(r io.Reader, boundary string) *multipartFramingScanner {
+	return &multipartFramingScanner{r: bufio.NewReader(r), boundary: boundary}
+}
+
+func isBoundaryDelimiterLine(content []byte, boundary string) bool {
+	s := string(content)
+	return s == "--"+boundary || s == "--"+boundary+"--"
+}
+
+// next returns the next chunk of the original byte stream verbatim,
+// together with whether it is framing material as opposed to body content.
+func (s *multipartFramingScanner) next() ([]byte, bool, error) {
+	if s.eof {
+		return nil, false, io.EOF
+	}
+
+	raw, _ := s.r.ReadBytes('\n')
+	if len(raw) == 0 {
+		if s.pendingTerm != nil {
+			tok := s.pendingTerm
+			s.pendingTerm = nil
+			s.eof = true
+			return tok, false, nil
+		}
+		s.eof = true
+		return nil, false, io.EOF
+	}
+
+	var content, term []byte
+	switch {
+	case len(raw) >= 2 && raw[len(raw)-1] == '\n' && raw[len(raw)-2] == '\r':
+		content, term = raw[:len(raw)-2], raw[len(raw)-2:]
+	case raw[len(raw)-1] == '\n':
+		content, term = raw[:len(raw)-1], raw[len(raw)-1:]
+	default:
+		content, term = raw, nil
+	}
+	if term == nil {
+		s.eof = true // last line of the stream had no terminator at all
+	}
+
+	if !s.inBody {
+		// A blank line only ends a part's headers; in the preamble (before
+		// the first delimiter) it is still framing.
+		if isBoundaryDelimiterLine(content, s.boundary) {
+			s.seenDelimiter = true
+		} else if len(content) == 0 && s.seenDelimiter {
+			s.inBody = true
+		}
+		return append(append([]byte{}, content...), term...), true, nil
+	}
+
+	if isBoundaryDelimiterLine(content, s.boundary) {
+		var tok []byte
+		if s.pendingTerm != nil {
+			tok = append(tok, s.pendingTerm...)
+			s.pendingTerm = nil
+		}
+		tok = append(tok, content...)
+		tok = append(tok, term...)
+		s.inBody = false
+		return tok, true, nil
+	}
+
+	var tok []byte
+	if s.pendingTerm != nil {
+		tok = append(tok, s.pendingTerm...)
+	}
+	tok = append(tok, content...)
+	s.pendingTerm = term
+	return tok, false, nil
+}
+
+// containsBareLF reports whether b contains a "\n" not immediately preceded
+// by "\r".
+func containsBareLF(b []byte) bool {
+	for i, c := range b {
+		if c == '\n' && (i == 0 || b[i-1] != '\r') {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeBareLF rewrites every "\n" in b not immediately preceded by "\r"
+// into "\r\n", incrementing *addedBytes once per rewrite.
+func normalizeBareLF(b []byte, addedBytes *int) []byte {
+	if !containsBareLF(b) {
+		return b
+	}
+	out := make([]byte, 0, len(b)+2)
+	for i := 0; i < len(b); i++ {
+		if b[i] == '\n' && (i == 0 || b[i-1] != '\r') {
+			out = append(out, '\r', '\n')
+			*addedBytes++
+		} else {
+			out = append(out, b[i])
+		}
+	}
+	return out
+}
+
+// bareLFDetectingReader rejects a "\n" line ending not preceded by "\r" in
+// multipart framing (preamble, headers, boundary delimiters), for "strict"
+// mode's refusal to tolerate any CRLF deviation there. A bare "\n" inside a
+// part's own body is left alone: body content isn't line-oriented framing,
+// and binary bodies legitimately contain 0x0A bytes.
+type bareLFDetectingReader struct {
+	scanner *multipartFramingScanner
+	out     []byte
+	err     error
+}
+
+func newBareLFDetectingReader(r io.Reader, boundary string) *bareLFDetectingReader {
+	return &bareLFDetectingReader{scanner: newMultipartFramingScanner(r, boundary)}
+}
+
+func (d *bareLFDetectingReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		tok, framing, err := d.scanner.next()
+		if err != nil {
+			d.err = err
+			continue
+		}
+		if framing && containsBareLF(tok) {
+			return 0, errBareLF
+		}
+		d.out = tok
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+// crlfNormalizingReader rewrites a bare "\n" line ending to "\r\n" in
+// multipart framing (preamble, headers, boundary delimiters) as bytes are
+// read, so "email" mode can accept real-world senders that use Unix line
+// endings. addedBytes is incremented once per inserted "\r". A part's own
+// body content is passed through untouched, since it isn't line-oriented
+// framing and may legitimately contain bare 0x0A bytes.
+type crlfNormalizingReader struct {
+	scanner    *multipartFramingScanner
+	addedBytes *int
+	out        []byte
+	err        error
+}
 
-	// Parse the Content-Type header to get the boundary
+func newCRLFNormalizingReader(r io.Reader, boundary string, addedBytes *int) *crlfNormalizingReader {
+	return &crlfNormalizingReader{scanner: newMultipartFramingScanner(r, boundary), addedBytes: addedBytes}
+}
+
+func (c *crlfNormalizingReader) Read(p []byte) (int, error) {
+	for len(c.out) == 0 {
+		if c.err != nil {
+			return 0, c.err
+		}
+		tok, framing, err := c.scanner.next()
+		if err != nil {
+			c.err = err
+			continue
+		}
+		if framing {
+			tok = normalizeBareLF(tok, c.addedBytes)
+		}
+		c.out = tok
+	}
+	n := copy(p, c.out)
+	c.out = c.out[n:]
+	return n, nil
+}
+
+// headerByteSize approximates the on-the-wire size of a part's headers, for
+// enforcing MaxHeaderBytes.
+func headerByteSize(h textproto.MIMEHeader) int64 {
+	var n int64
+	for k, vs := range h {
+		for _, v := range vs {
+			// key + ": " + value + CRLF
+			n += int64(len(k)) + int64(len(v)) + 4
+		}
+	}
+	return n
+}
+
+// resolveBoundary parses the Content-Type header and extracts the multipart
+// boundary parameter. errType is ErrorTypeBoundaryMissing when the boundary
+// parameter itself is absent, or ErrorTypeParseError for any other
+// Content-Type problem, so callers can report it the way writeError/
+// streamErrorEvent expect.
+func resolveBoundary(r *http.Request) (boundary string, errType string, err error) {
 	contentType := r.Header.Get("Content-Type")
 	mediaType, params, err := mime.ParseMediaType(contentType)
 	if err != nil {
-		writeError(w, "parse_error", fmt.Sprintf("failed to parse Content-Type: %v", err))
-		return
+		return "", ErrorTypeParseError, fmt.Errorf("failed to parse Content-Type: %w", err)
 	}
 
 	if !strings.HasPrefix(mediaType, "multipart/") {
-		writeError(w, "parse_error", fmt.Sprintf("expected multipart content type, got: %s", mediaType))
-		return
+		return "", ErrorTypeParseError, fmt.Errorf("expected multipart content type, got: %s", mediaType)
 	}
 
 	boundary, ok := params["boundary"]
 	if !ok {
-		writeError(w, "boundary_mismatch", "missing boundary parameter in Content-Type")
+		return "", ErrorTypeBoundaryMissing, errors.New("missing boundary parameter in Content-Type")
+	}
+
+	return boundary, "", nil
+}
+
+// wrapRequestBodyForMode wraps r.Body (in place) with the CRLF-normalizing,
+// bare-LF-detecting and/or total-size-limiting readers implied by mode and
+// opts, shared by parseMultipart and parseMultipartStream. boundary scopes
+// the CRLF/bare-LF handling to multipart framing, leaving part bodies
+// untouched (see multipartFramingScanner). It returns a pointer to the
+// normalized-byte counter that "email" mode fills in as it reads.
+func wrapRequestBodyForMode(r *http.Request, opts ParserOptions, mode, boundary string) *int {
+	normalizedBytes := 0
+
+	switch mode {
+	case "email":
+		r.Body = io.NopCloser(newCRLFNormalizingReader(r.Body, boundary, &normalizedBytes))
+	case "strict":
+		r.Body = io.NopCloser(newBareLFDetectingReader(r.Body, boundary))
+	}
+
+	if opts.MaxTotalSize > 0 {
+		r.Body = io.NopCloser(&limitedBodyReader{r: r.Body, remaining: opts.MaxTotalSize})
+	}
+
+	return &normalizedBytes
+}
+
+// parseMultipart handles POST /parse requests.
+func parseMultipart(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	opts, err := parserOptionsFromRequest(r)
+	if err != nil {
+		writeError(w, ErrorTypeParseError, err.Error())
+		return
+	}
+
+	boundary, errType, err := resolveBoundary(r)
+	if err != nil {
+		writeError(w, errType, err.Error())
+		return
+	}
+
+	mode, err := resolveMode(r, opts)
+	if err != nil {
+		writeError(w, ErrorTypeParseError, err.Error())
+		return
+	}
+
+	normalizedBytes := wrapRequestBodyForMode(r, opts, mode, boundary)
+
+	if opts.MaxMemory > 0 {
+		parseMultipartWithMemoryLimit(w, r, boundary, opts, mode, normalizedBytes)
 		return
 	}
 
@@ -75,84 +488,375 @@ func parseMultipart(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// Read the part body
-		body, err := io.ReadAll(part)
+		if opts.MaxParts > 0 && len(parts) >= opts.MaxParts {
+			writeError(w, classifyError(errTooManyParts), errTooManyParts.Error())
+			return
+		}
+
+		if opts.MaxHeaderBytes > 0 && headerByteSize(part.Header) > opts.MaxHeaderBytes {
+			writeError(w, classifyError(errHeaderTooLarge), errHeaderTooLarge.Error())
+			return
+		}
+
+		// Read the part body, honoring MaxPartSize if set
+		var body []byte
+		if opts.MaxPartSize > 0 {
+			body, err = io.ReadAll(io.LimitReader(part, opts.MaxPartSize+1))
+			if err == nil && int64(len(body)) > opts.MaxPartSize {
+				writeError(w, classifyError(errPartTooLarge), errPartTooLarge.Error())
+				return
+			}
+		} else {
+			body, err = io.ReadAll(part)
+		}
 		if err != nil {
-			writeError(w, "parse_error", fmt.Sprintf("failed to read part body: %v", err))
+			errType := classifyError(err)
+			writeError(w, errType, err.Error())
 			return
 		}
 
 		// Get form field name from Content-Disposition
 		name := part.FormName()
 
-		// Get filename (if present)
-		var filename *string
-		fn := part.FileName()
-		// Check if filename parameter was present in Content-Disposition
-		// part.FileName() returns empty string for both no-filename and filename=""
-		// We need to check the raw header to distinguish
-		cd := part.Header.Get("Content-Disposition")
-		if hasFilenameParam(cd) {
-			filename = &fn
+		built, err := buildPart(name, part.Header, body, false, opts, mode)
+		if err != nil {
+			writeError(w, classifyError(err), err.Error())
+			return
 		}
 
-		// Get Content-Type (if present)
-		var contentType *string
-		ct := part.Header.Get("Content-Type")
-		if ct != "" {
-			contentType = &ct
+		parts = append(parts, built)
+	}
+
+	response := ParseResponse{
+		Valid:           true,
+		Parts:           parts,
+		Mode:            mode,
+		NormalizedBytes: *normalizedBytes,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseMultipartWithMemoryLimit handles the MaxMemory case. It drives its own
+// multipart.Reader loop, the same as parseMultipart, rather than delegating
+// to Request.ParseMultipartForm: ParseMultipartForm buckets parts into its
+// Value/File maps, which loses wire order and discards headers for non-file
+// parts. Here every part is built through buildPart so parts come out in
+// wire order with identical shapes regardless of size, and a running
+// memRemaining budget decides which parts spill to a temp file on disk
+// instead of staying in memory, mirroring ParseMultipartForm's own spill
+// behavior.
+func parseMultipartWithMemoryLimit(w http.ResponseWriter, r *http.Request, boundary string, opts ParserOptions, mode string, normalizedBytes *int) {
+	reader := multipart.NewReader(r.Body, boundary)
+	defer r.Body.Close()
+
+	var parts []Part
+	var spillFiles []*os.File
+	defer func() {
+		for _, f := range spillFiles {
+			f.Close()
+			os.Remove(f.Name())
 		}
+	}()
 
-		// Determine body representation (text or base64)
-		var bodyText *string
-		var bodyBase64 *string
+	memRemaining := opts.MaxMemory
 
-		if utf8.Valid(body) {
-			s := string(body)
-			bodyText = &s
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errType := classifyError(err)
+			writeError(w, errType, err.Error())
+			return
+		}
+
+		if opts.MaxParts > 0 && len(parts) >= opts.MaxParts {
+			writeError(w, classifyError(errTooManyParts), errTooManyParts.Error())
+			return
+		}
+
+		if opts.MaxHeaderBytes > 0 && headerByteSize(part.Header) > opts.MaxHeaderBytes {
+			writeError(w, classifyError(errHeaderTooLarge), errHeaderTooLarge.Error())
+			return
+		}
+
+		var body []byte
+		if opts.MaxPartSize > 0 {
+			body, err = io.ReadAll(io.LimitReader(part, opts.MaxPartSize+1))
+			if err == nil && int64(len(body)) > opts.MaxPartSize {
+				writeError(w, classifyError(errPartTooLarge), errPartTooLarge.Error())
+				return
+			}
 		} else {
-			s := base64.StdEncoding.EncodeToString(body)
-			bodyBase64 = &s
+			body, err = io.ReadAll(part)
+		}
+		if err != nil {
+			errType := classifyError(err)
+			writeError(w, errType, err.Error())
+			return
 		}
 
-		parts = append(parts, Part{
-			Name:        name,
-			Filename:    filename,
-			ContentType: contentType,
-			BodyText:    bodyText,
-			BodyBase64:  bodyBase64,
-			BodySize:    len(body),
-		})
+		spilled := int64(len(body)) > memRemaining
+		if spilled {
+			f, err := os.CreateTemp("", "multipart-spill-")
+			if err != nil {
+				writeError(w, ErrorTypeParseError, fmt.Sprintf("failed to spill part to disk: %v", err))
+				return
+			}
+			spillFiles = append(spillFiles, f)
+			if _, err := f.Write(body); err != nil {
+				writeError(w, ErrorTypeParseError, fmt.Sprintf("failed to spill part to disk: %v", err))
+				return
+			}
+			memRemaining = 0
+		} else {
+			memRemaining -= int64(len(body))
+		}
+
+		name := part.FormName()
+
+		built, err := buildPart(name, part.Header, body, spilled, opts, mode)
+		if err != nil {
+			writeError(w, classifyError(err), err.Error())
+			return
+		}
+
+		parts = append(parts, built)
 	}
 
 	response := ParseResponse{
-		Valid: true,
-		Parts: parts,
+		Valid:           true,
+		Parts:           parts,
+		Mode:            mode,
+		NormalizedBytes: *normalizedBytes,
 	}
 
 	json.NewEncoder(w).Encode(response)
 }
 
-// hasFilenameParam checks if the Content-Disposition header contains a filename parameter.
-func hasFilenameParam(cd string) bool {
-	// Look for filename= or filename*= in the header
-	lower := strings.ToLower(cd)
-	return strings.Contains(lower, "filename=") || strings.Contains(lower, "filename*=")
+var (
+	// filenameStarParamRe matches a filename*= extended parameter so we can
+	// tell it apart from a plain filename= one and, when both are present,
+	// strip it back out to recover the plain value.
+	filenameStarParamRe  = regexp.MustCompile(`(?i);\s*filename\*\s*=\s*("[^"]*"|[^;]*)`)
+	plainFilenameParamRe = regexp.MustCompile(`(?i);\s*filename\s*=`)
+
+	mimeWordDecoder = &mime.WordDecoder{}
+)
+
+// parseContentDispositionFilename extracts the filename= and filename*=
+// parameters from a Content-Disposition header value using mime.ParseMediaType,
+// decoding filename= per RFC 2047 (encoded-words) and filename*= per RFC
+// 5987/2231 (charset'lang'pct-encoded). The two are kept distinct so
+// conformance tests can tell which form a server actually honored.
+func parseContentDispositionFilename(cd string) (filename, filenameStar *string, err error) {
+	if cd == "" {
+		// No Content-Disposition at all is unusual but not fatal: the part
+		// is just nameless, the same as the baseline parser treated it.
+		return nil, nil, nil
+	}
+
+	hasStar := filenameStarParamRe.MatchString(cd)
+
+	_, params, err := mime.ParseMediaType(cd)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errInvalidContentDisposition, err)
+	}
+
+	if !hasStar {
+		if v, ok := params["filename"]; ok {
+			decoded := decodeRFC2047(v)
+			filename = &decoded
+		}
+		return filename, nil, nil
+	}
+
+	// mime.ParseMediaType prefers filename* when both forms are present,
+	// storing its already charset-decoded value under the plain "filename"
+	// key. Recover a plain filename=, if one was also sent, by stripping
+	// the extended parameter and re-parsing.
+	if v, ok := params["filename"]; ok {
+		filenameStar = &v
+	}
+	if plainFilenameParamRe.MatchString(cd) {
+		stripped := filenameStarParamRe.ReplaceAllString(cd, "")
+		if _, plainParams, err := mime.ParseMediaType(stripped); err == nil {
+			if v, ok := plainParams["filename"]; ok {
+				decoded := decodeRFC2047(v)
+				filename = &decoded
+			}
+		}
+	}
+
+	return filename, filenameStar, nil
+}
+
+// decodeRFC2047 decodes RFC 2047 encoded-words (e.g. "=?UTF-8?B?...?=") that
+// may appear in a plain filename= parameter. Values that aren't encoded-words
+// are returned unchanged.
+func decodeRFC2047(s string) string {
+	decoded, err := mimeWordDecoder.DecodeHeader(s)
+	if err != nil {
+		return s
+	}
+	return decoded
+}
+
+// decodableCTE reports whether a Content-Transfer-Encoding value is one this
+// server knows how to decode.
+func decodableCTE(cte string) bool {
+	switch strings.ToLower(strings.TrimSpace(cte)) {
+	case "base64", "quoted-printable":
+		return true
+	default:
+		return false
+	}
+}
+
+// basicCTE reports whether a Content-Transfer-Encoding value is one of the
+// identity encodings "strict" mode requires (or no CTE at all).
+func basicCTE(cte string) bool {
+	switch strings.ToLower(strings.TrimSpace(cte)) {
+	case "", "7bit", "8bit", "binary":
+		return true
+	default:
+		return false
+	}
+}
+
+// decodeTransferEncoding decodes a part body per its Content-Transfer-Encoding.
+func decodeTransferEncoding(cte string, body []byte) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(cte)) {
+	case "base64":
+		decoded := make([]byte, base64.StdEncoding.DecodedLen(len(body)))
+		n, err := base64.StdEncoding.Decode(decoded, body)
+		if err != nil {
+			return nil, err
+		}
+		return decoded[:n], nil
+	case "quoted-printable":
+		return io.ReadAll(quotedprintable.NewReader(bytes.NewReader(body)))
+	default:
+		return body, nil
+	}
+}
+
+// bodyFields splits a part body into the text or base64 JSON representation,
+// depending on whether it's valid UTF-8.
+func bodyFields(body []byte) (text *string, b64 *string) {
+	if utf8.Valid(body) {
+		s := string(body)
+		return &s, nil
+	}
+	s := base64.StdEncoding.EncodeToString(body)
+	return nil, &s
 }
 
-// classifyError maps common multipart parsing errors to error types.
+// validatePartHeader extracts the filename/filename* parameters from a
+// part's Content-Disposition header and, in "strict" mode, rejects any
+// Content-Transfer-Encoding other than the identity encodings. It requires
+// only the headers, not the body, so both buildPart and the streaming
+// /parse/stream handler can run it before committing to reading (and for
+// /parse/stream, hashing) the part body.
+func validatePartHeader(header textproto.MIMEHeader, mode string) (filename, filenameStar *string, err error) {
+	filename, filenameStar, err = parseContentDispositionFilename(header.Get("Content-Disposition"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if mode == "strict" && !basicCTE(header.Get("Content-Transfer-Encoding")) {
+		return nil, nil, errUnsupportedCTE
+	}
+
+	return filename, filenameStar, nil
+}
+
+// buildPart assembles a Part from a part's headers and already-read body,
+// shared by parseMultipart and parseMultipartWithMemoryLimit (the MaxMemory
+// path) so the two produce identical shapes.
+func buildPart(name string, header textproto.MIMEHeader, body []byte, spilled bool, opts ParserOptions, mode string) (Part, error) {
+	part := Part{
+		Name:          name,
+		Headers:       map[string][]string(header),
+		BodySize:      len(body),
+		SpilledToDisk: spilled,
+	}
+
+	filename, filenameStar, err := validatePartHeader(header, mode)
+	if err != nil {
+		return Part{}, err
+	}
+	part.Filename = filename
+	part.FilenameStar = filenameStar
+
+	if ct := header.Get("Content-Type"); ct != "" {
+		part.ContentType = &ct
+	}
+
+	var cte string
+	if v := header.Get("Content-Transfer-Encoding"); v != "" {
+		cte = v
+		part.ContentTransferEncoding = &v
+	}
+
+	if (mode == "email" || opts.DecodeTransferEncoding) && decodableCTE(cte) {
+		if decoded, err := decodeTransferEncoding(cte, body); err == nil {
+			part.RawBodyText, part.RawBodyBase64 = bodyFields(body)
+			part.BodyText, part.BodyBase64 = bodyFields(decoded)
+			part.BodySize = len(decoded)
+			return part, nil
+		}
+	}
+
+	part.BodyText, part.BodyBase64 = bodyFields(body)
+	return part, nil
+}
+
+// classifyError maps a parsing error to one of the stable error_type values,
+// driven by errors.Is/As against sentinel errors from this package,
+// mime/multipart and net/textproto, rather than matching substrings of
+// err.Error() (which is brittle across Go versions and locales).
+//
+// Go's mime/multipart doesn't surface distinct errors for every case: a
+// mismatched or missing closing boundary shows up as io.ErrUnexpectedEOF
+// inside the last part (unterminated_part), and input that ends while
+// NextPart is looking for a delimiter shows up as a wrapped io.EOF
+// (epilogue_eof) whether or not any part came before it. So
+// boundary_mismatch and preamble_eof can't actually be produced by this
+// backend, and neither can missing_content_disposition, since a part with no
+// Content-Disposition is accepted (see parseContentDispositionFilename);
+// they're included here only because they're part of the shared taxonomy
+// other backends do report.
 func classifyError(err error) string {
-	msg := strings.ToLower(err.Error())
 	switch {
-	case strings.Contains(msg, "boundary"):
-		return "boundary_mismatch"
-	case strings.Contains(msg, "content-disposition"):
-		return "missing_content_disposition"
-	case strings.Contains(msg, "header"):
-		return "invalid_header"
-	default:
-		return "parse_error"
+	case errors.Is(err, errTooManyParts):
+		return ErrorTypeTooManyParts
+	case errors.Is(err, errPartTooLarge):
+		return ErrorTypePartTooLarge
+	case errors.Is(err, multipart.ErrMessageTooLarge):
+		return ErrorTypePartTooLarge
+	case errors.Is(err, errHeaderTooLarge):
+		return ErrorTypeHeaderTooLarge
+	case errors.Is(err, errBodyTooLarge):
+		return ErrorTypeIOError
+	case errors.Is(err, errMissingContentDisposition):
+		return ErrorTypeMissingContentDisposition
+	case errors.Is(err, errInvalidContentDisposition), errors.Is(err, mime.ErrInvalidMediaParameter):
+		return ErrorTypeInvalidContentDisposition
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorTypeUnterminatedPart
+	case errors.Is(err, io.EOF):
+		return ErrorTypeEpilogueEOF
 	}
+
+	var protoErr textproto.ProtocolError
+	if errors.As(err, &protoErr) {
+		return ErrorTypeMalformedHeader
+	}
+
+	return ErrorTypeIOError
 }
 
 // writeError writes a JSON error response.
@@ -165,6 +869,167 @@ func writeError(w http.ResponseWriter, errType, errMessage string) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// streamChunkSize is the read buffer size used by parseMultipartStream, and
+// so the largest body_chunk event emitted when ?include_body=1.
+const streamChunkSize = 64 * 1024
+
+// streamPartEvent is the NDJSON line emitted once per parsed part by
+// parseMultipartStream.
+type streamPartEvent struct {
+	Event      string              `json:"event"`
+	Index      int                 `json:"index"`
+	Name       string              `json:"name"`
+	Headers    map[string][]string `json:"headers,omitempty"`
+	BodySHA256 string              `json:"body_sha256"`
+	BodySize   int                 `json:"body_size"`
+}
+
+// streamBodyChunkEvent is an optional NDJSON line emitted for each read of
+// a part's body (at most streamChunkSize bytes, often fewer), when the
+// caller set ?include_body=1.
+type streamBodyChunkEvent struct {
+	Event  string `json:"event"`
+	Index  int    `json:"index"`
+	Base64 string `json:"base64"`
+}
+
+// streamEndEvent is the final NDJSON line on success.
+type streamEndEvent struct {
+	Event string `json:"event"`
+	Valid bool   `json:"valid"`
+}
+
+// streamErrorEvent is the final NDJSON line on failure, reporting how many
+// parts were successfully emitted before the error was hit.
+type streamErrorEvent struct {
+	Event        string `json:"event"`
+	ErrorType    string `json:"error_type"`
+	ErrorMessage string `json:"error_message"`
+	PartialParts int    `json:"partial_parts"`
+}
+
+// writeStreamEvent encodes a single NDJSON line and flushes it immediately,
+// so a client sees each part as it is parsed rather than waiting for the
+// whole body to be read.
+func writeStreamEvent(w http.ResponseWriter, enc *json.Encoder, v any) {
+	enc.Encode(v)
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// parseMultipartStream handles POST /parse/stream requests. Unlike
+// parseMultipart, it never buffers the full set of parts: each part's body
+// is read in streamChunkSize chunks, hashed incrementally with SHA-256, and
+// reported as soon as it has been fully read, so conformance tests can
+// observe streaming-visible behavior (e.g. whether parts are emitted before
+// a malformed trailer is encountered) and so arbitrarily large uploads don't
+// need to fit in memory at once.
+func parseMultipartStream(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	// Without full duplex, net/http closes the request body on the first
+	// flush, before the rest of the multipart body has been read.
+	http.NewResponseController(w).EnableFullDuplex()
+	enc := json.NewEncoder(w)
+
+	opts, err := parserOptionsFromRequest(r)
+	if err != nil {
+		writeStreamEvent(w, enc, streamErrorEvent{Event: "error", ErrorType: ErrorTypeParseError, ErrorMessage: err.Error()})
+		return
+	}
+
+	if opts.MaxMemory > 0 {
+		writeStreamEvent(w, enc, streamErrorEvent{Event: "error", ErrorType: ErrorTypeParseError, ErrorMessage: "max_memory is not supported by /parse/stream, which never buffers part bodies into memory; use /parse instead"})
+		return
+	}
+
+	boundary, errType, err := resolveBoundary(r)
+	if err != nil {
+		writeStreamEvent(w, enc, streamErrorEvent{Event: "error", ErrorType: errType, ErrorMessage: err.Error()})
+		return
+	}
+
+	mode, err := resolveMode(r, opts)
+	if err != nil {
+		writeStreamEvent(w, enc, streamErrorEvent{Event: "error", ErrorType: ErrorTypeParseError, ErrorMessage: err.Error()})
+		return
+	}
+
+	wrapRequestBodyForMode(r, opts, mode, boundary)
+
+	includeBody := r.URL.Query().Get("include_body") == "1"
+
+	reader := multipart.NewReader(r.Body, boundary)
+	defer r.Body.Close()
+
+	index := 0
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeStreamEvent(w, enc, streamErrorEvent{Event: "error", ErrorType: classifyError(err), ErrorMessage: err.Error(), PartialParts: index})
+			return
+		}
+
+		if opts.MaxParts > 0 && index >= opts.MaxParts {
+			writeStreamEvent(w, enc, streamErrorEvent{Event: "error", ErrorType: classifyError(errTooManyParts), ErrorMessage: errTooManyParts.Error(), PartialParts: index})
+			return
+		}
+
+		if opts.MaxHeaderBytes > 0 && headerByteSize(part.Header) > opts.MaxHeaderBytes {
+			writeStreamEvent(w, enc, streamErrorEvent{Event: "error", ErrorType: classifyError(errHeaderTooLarge), ErrorMessage: errHeaderTooLarge.Error(), PartialParts: index})
+			return
+		}
+
+		if _, _, err := validatePartHeader(part.Header, mode); err != nil {
+			writeStreamEvent(w, enc, streamErrorEvent{Event: "error", ErrorType: classifyError(err), ErrorMessage: err.Error(), PartialParts: index})
+			return
+		}
+
+		name := part.FormName()
+		hasher := sha256.New()
+		buf := make([]byte, streamChunkSize)
+		bodySize := 0
+
+		for {
+			n, rerr := part.Read(buf)
+			if n > 0 {
+				chunk := buf[:n]
+				hasher.Write(chunk)
+				bodySize += n
+				if opts.MaxPartSize > 0 && int64(bodySize) > opts.MaxPartSize {
+					writeStreamEvent(w, enc, streamErrorEvent{Event: "error", ErrorType: classifyError(errPartTooLarge), ErrorMessage: errPartTooLarge.Error(), PartialParts: index})
+					return
+				}
+				if includeBody {
+					writeStreamEvent(w, enc, streamBodyChunkEvent{Event: "body_chunk", Index: index, Base64: base64.StdEncoding.EncodeToString(chunk)})
+				}
+			}
+			if rerr == io.EOF {
+				break
+			}
+			if rerr != nil {
+				writeStreamEvent(w, enc, streamErrorEvent{Event: "error", ErrorType: classifyError(rerr), ErrorMessage: rerr.Error(), PartialParts: index})
+				return
+			}
+		}
+
+		writeStreamEvent(w, enc, streamPartEvent{
+			Event:      "part",
+			Index:      index,
+			Name:       name,
+			Headers:    map[string][]string(part.Header),
+			BodySHA256: hex.EncodeToString(hasher.Sum(nil)),
+			BodySize:   bodySize,
+		})
+		index++
+	}
+
+	writeStreamEvent(w, enc, streamEndEvent{Event: "end", Valid: true})
+}
+
 // healthCheck handles GET /health requests.
 func healthCheck(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -173,6 +1038,7 @@ func healthCheck(w http.ResponseWriter, r *http.Request) {
 
 func main() {
 	http.HandleFunc("/parse", parseMultipart)
+	http.HandleFunc("/parse/stream", parseMultipartStream)
 	http.HandleFunc("/health", healthCheck)
 
 	port := "8080"
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -17,13 +20,13 @@ import (
 
 // TestJSON represents the test.json file structure.
 type TestJSON struct {
-	ID            string            `json:"id"`
-	Name          string            `json:"name"`
-	Description   string            `json:"description"`
-	Category      string            `json:"category"`
-	Tags          []string          `json:"tags"`
-	Expected      ExpectedResult    `json:"expected"`
-	ParserOptions map[string]any    `json:"parser_options,omitempty"`
+	ID            string         `json:"id"`
+	Name          string         `json:"name"`
+	Description   string         `json:"description"`
+	Category      string         `json:"category"`
+	Tags          []string       `json:"tags"`
+	Expected      ExpectedResult `json:"expected"`
+	ParserOptions map[string]any `json:"parser_options,omitempty"`
 }
 
 // ExpectedResult represents the expected parsing result.
@@ -35,12 +38,14 @@ type ExpectedResult struct {
 
 // ExpectedPart represents an expected parsed part.
 type ExpectedPart struct {
-	Name        string  `json:"name"`
-	Filename    *string `json:"filename"`
-	ContentType *string `json:"content_type"`
-	BodyText    *string `json:"body_text"`
-	BodyBase64  *string `json:"body_base64"`
-	BodySize    int     `json:"body_size"`
+	Name          string  `json:"name"`
+	Filename      *string `json:"filename"`
+	FilenameStar  *string `json:"filename_star,omitempty"`
+	ContentType   *string `json:"content_type"`
+	BodyText      *string `json:"body_text"`
+	BodyBase64    *string `json:"body_base64"`
+	BodySize      int     `json:"body_size"`
+	SpilledToDisk bool    `json:"spilled_to_disk,omitempty"`
 }
 
 // HeadersJSON represents the headers.json file structure.
@@ -167,6 +172,7 @@ func startTestServer(t *testing.T) *httptest.Server {
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/parse", parseMultipart)
+	mux.HandleFunc("/parse/stream", parseMultipartStream)
 	mux.HandleFunc("/health", healthCheck)
 
 	return httptest.NewServer(mux)
@@ -192,6 +198,15 @@ func comparePart(actual Part, expected ExpectedPart) []string {
 		mismatches = append(mismatches, fmt.Sprintf("filename mismatch: got %q, expected nil", *actual.Filename))
 	}
 
+	// Compare filename* (RFC 5987/2231)
+	if expected.FilenameStar != nil {
+		if actual.FilenameStar == nil {
+			mismatches = append(mismatches, fmt.Sprintf("filename_star mismatch: got nil, expected %q", *expected.FilenameStar))
+		} else if *actual.FilenameStar != *expected.FilenameStar {
+			mismatches = append(mismatches, fmt.Sprintf("filename_star mismatch: got %q, expected %q", *actual.FilenameStar, *expected.FilenameStar))
+		}
+	}
+
 	// Compare content_type (only if expected specifies one)
 	if expected.ContentType != nil {
 		if actual.ContentType == nil {
@@ -256,6 +271,12 @@ func TestConformance(t *testing.T) {
 		t.Run(tc.ID, func(t *testing.T) {
 			runTestCase(t, server.URL, tc)
 		})
+		t.Run(tc.ID+"/stream", func(t *testing.T) {
+			if reason := streamUnsupportedReason(tc.Test.ParserOptions); reason != "" {
+				t.Skipf("/parse/stream does not support this fixture: %s", reason)
+			}
+			runStreamTestCase(t, server.URL, tc)
+		})
 	}
 }
 
@@ -277,6 +298,16 @@ func runTestCase(t *testing.T, baseURL string, tc TestCase) {
 		req.Header.Set(key, value)
 	}
 
+	// Forward parser_options from test.json, if present, as the
+	// X-Parser-Options header the server expects.
+	if len(tc.Test.ParserOptions) > 0 {
+		optsJSON, err := json.Marshal(tc.Test.ParserOptions)
+		if err != nil {
+			t.Fatalf("failed to marshal parser_options: %v", err)
+		}
+		req.Header.Set("X-Parser-Options", string(optsJSON))
+	}
+
 	// Send request
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -340,10 +371,214 @@ func runTestCase(t *testing.T, baseURL string, tc TestCase) {
 			// Go stdlib successfully parsed what we expected to fail
 			// This is OK - it's just more lenient than the spec requires
 			t.Skipf("Go stdlib parsed malformed input %s successfully (lenient parsing)", tc.ID)
+			return
+		}
+
+		if expected.ErrorType != "" {
+			var gotType string
+			if result.ErrorType != nil {
+				gotType = *result.ErrorType
+			}
+			if gotType != expected.ErrorType {
+				msg := fmt.Sprintf("error_type mismatch for %s: got %q, expected %q", tc.ID, gotType, expected.ErrorType)
+				if optional || knownLenientErrorTypes[expected.ErrorType] {
+					t.Skip(msg)
+				}
+				t.Fatal(msg)
+			}
+		}
+	}
+}
+
+// knownLenientErrorTypes lists expected error_type values the Go stdlib
+// backend can't reliably reproduce, because mime/multipart doesn't
+// distinguish them from each other or from a lenient successful parse. A
+// mismatch against one of these is a backend limitation, not a conformance
+// failure.
+var knownLenientErrorTypes = map[string]bool{
+	"boundary_mismatch": true,
+	"preamble_eof":      true,
+	"epilogue_eof":      true,
+}
+
+// streamUnsupportedReason reports why a fixture's parser_options can't be
+// run against /parse/stream, or "" if it can. /parse/stream rejects
+// max_memory outright (it never buffers part bodies into memory), and it
+// never applies Content-Transfer-Encoding decoding, whether requested by
+// decode_transfer_encoding or implied by "email" mode, so a fixture
+// exercising those would compare the stream's raw body hash against
+// /parse's decoded expectations and spuriously fail on a gap the streaming
+// endpoint cannot close.
+func streamUnsupportedReason(opts map[string]any) string {
+	if v, ok := opts["max_memory"]; ok {
+		if f, ok := v.(float64); ok && f != 0 {
+			return "max_memory is not supported by /parse/stream"
+		}
+	}
+	if v, ok := opts["decode_transfer_encoding"]; ok {
+		if b, ok := v.(bool); ok && b {
+			return "decode_transfer_encoding is not applied by /parse/stream"
+		}
+	}
+	if v, ok := opts["mode"]; ok {
+		if s, ok := v.(string); ok && s == "email" {
+			return "email mode Content-Transfer-Encoding decoding is not applied by /parse/stream"
+		}
+	}
+	return ""
+}
+
+// runStreamTestCase runs a single test case against the NDJSON /parse/stream
+// endpoint, checking its parts (name, body_size, and body SHA-256 rather
+// than content, since the stream endpoint never returns full bodies) and its
+// error_type against the same fixture expectations runTestCase checks the
+// buffered /parse endpoint against.
+func runStreamTestCase(t *testing.T, baseURL string, tc TestCase) {
+	optional := isOptional(tc)
+
+	req, err := http.NewRequest("POST", baseURL+"/parse/stream", bytes.NewReader(tc.InputRaw))
+	if err != nil {
+		if optional {
+			t.Skipf("Optional test %s: failed to create request: %v", tc.ID, err)
+		}
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	for key, value := range tc.Headers {
+		req.Header.Set(key, value)
+	}
+
+	if len(tc.Test.ParserOptions) > 0 {
+		optsJSON, err := json.Marshal(tc.Test.ParserOptions)
+		if err != nil {
+			t.Fatalf("failed to marshal parser_options: %v", err)
+		}
+		req.Header.Set("X-Parser-Options", string(optsJSON))
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		if optional {
+			t.Skipf("Optional test %s: HTTP client error: %v", tc.ID, err)
 		}
-		// Optionally check error type matches
-		// For now we just verify it failed
+		t.Fatalf("HTTP request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var parts []streamPartEvent
+	var endEvent *streamEndEvent
+	var errEvent *streamErrorEvent
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var probe struct {
+			Event string `json:"event"`
+		}
+		if err := json.Unmarshal(line, &probe); err != nil {
+			t.Fatalf("failed to decode NDJSON line %q: %v", line, err)
+		}
+
+		switch probe.Event {
+		case "part":
+			var p streamPartEvent
+			json.Unmarshal(line, &p)
+			parts = append(parts, p)
+		case "end":
+			var e streamEndEvent
+			json.Unmarshal(line, &e)
+			endEvent = &e
+		case "error":
+			var e streamErrorEvent
+			json.Unmarshal(line, &e)
+			errEvent = &e
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("failed to read NDJSON stream: %v", err)
+	}
+
+	expected := tc.Test.Expected
+
+	if expected.Valid {
+		if errEvent != nil {
+			if optional {
+				t.Skipf("Optional test %s: /parse/stream reported error: %s", tc.ID, errEvent.ErrorMessage)
+			}
+			t.Fatalf("expected valid parsing via /parse/stream, but got error: %s", errEvent.ErrorMessage)
+		}
+		if endEvent == nil || !endEvent.Valid {
+			t.Fatalf("/parse/stream did not report a valid end event")
+		}
+
+		if len(parts) != len(expected.Parts) {
+			if optional {
+				t.Skipf("Optional test %s: stream part count mismatch (got %d, expected %d)", tc.ID, len(parts), len(expected.Parts))
+			}
+			t.Fatalf("stream part count mismatch: got %d, expected %d", len(parts), len(expected.Parts))
+		}
+
+		var allMismatches []string
+		for i, exp := range expected.Parts {
+			got := parts[i]
+			if got.Name != exp.Name {
+				allMismatches = append(allMismatches, fmt.Sprintf("Part %d: name mismatch: got %q, expected %q", i, got.Name, exp.Name))
+			}
+			if wantHash := expectedBodySHA256(exp); wantHash != "" && got.BodySHA256 != wantHash {
+				allMismatches = append(allMismatches, fmt.Sprintf("Part %d: body_sha256 mismatch: got %s, expected %s", i, got.BodySHA256, wantHash))
+			}
+			if got.BodySize != exp.BodySize {
+				allMismatches = append(allMismatches, fmt.Sprintf("Part %d: body_size mismatch: got %d, expected %d", i, got.BodySize, exp.BodySize))
+			}
+		}
+		if len(allMismatches) > 0 {
+			if optional {
+				t.Skipf("Optional test %s failed:\n%s", tc.ID, strings.Join(allMismatches, "\n"))
+			}
+			t.Fatalf("stream part comparison failed:\n%s", strings.Join(allMismatches, "\n"))
+		}
+	} else {
+		if errEvent == nil {
+			t.Skipf("/parse/stream parsed malformed input %s successfully (lenient parsing)", tc.ID)
+			return
+		}
+
+		if expected.ErrorType != "" && errEvent.ErrorType != expected.ErrorType {
+			msg := fmt.Sprintf("stream error_type mismatch for %s: got %q, expected %q", tc.ID, errEvent.ErrorType, expected.ErrorType)
+			if optional || knownLenientErrorTypes[expected.ErrorType] {
+				t.Skip(msg)
+			}
+			t.Fatal(msg)
+		}
+	}
+}
+
+// expectedBodySHA256 computes the SHA-256 hash an ExpectedPart's body would
+// produce, for comparison against a streamPartEvent.BodySHA256. Returns ""
+// if the fixture specifies no body content to check.
+func expectedBodySHA256(exp ExpectedPart) string {
+	if exp.BodyText != nil {
+		return sha256Hex([]byte(*exp.BodyText))
+	}
+	if exp.BodyBase64 != nil {
+		decoded, err := base64.StdEncoding.DecodeString(*exp.BodyBase64)
+		if err != nil {
+			return ""
+		}
+		return sha256Hex(decoded)
 	}
+	return ""
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
 }
 
 // TestServerHealth tests the health check endpoint.